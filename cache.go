@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/sonm-io/core/proto"
+)
+
+// searchCache is a short-lived, in-memory cache of /search/ results keyed
+// by the normalized filter, so repeated dashboard refreshes don't hammer
+// the marketplace gRPC endpoint.
+type searchCache struct {
+	mu       sync.RWMutex
+	ttl      time.Duration
+	entries  map[string]searchCacheEntry
+	disabled bool
+}
+
+type searchCacheEntry struct {
+	orders    []*pb.Order
+	expiresAt time.Time
+}
+
+// newSearchCache builds a cache with the given TTL. A non-positive ttl
+// disables caching entirely instead of passing it to time.NewTicker, which
+// panics on a non-positive interval.
+func newSearchCache(ttl time.Duration) *searchCache {
+	c := &searchCache{ttl: ttl, entries: make(map[string]searchCacheEntry)}
+	if ttl <= 0 {
+		c.disabled = true
+		return c
+	}
+
+	go c.janitor()
+	return c
+}
+
+// janitor periodically sweeps expired entries so keys that are never
+// looked up again (every distinct filter combination gets its own key)
+// don't accumulate in entries forever.
+func (c *searchCache) janitor() {
+	ticker := time.NewTicker(c.ttl)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		c.mu.Lock()
+		for key, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+func (c *searchCache) get(key string) ([]*pb.Order, bool) {
+	if c.disabled {
+		return nil, false
+	}
+
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(entry.expiresAt) {
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.mu.Unlock()
+		return nil, false
+	}
+
+	return entry.orders, true
+}
+
+func (c *searchCache) set(key string, orders []*pb.Order) {
+	if c.disabled {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = searchCacheEntry{orders: orders, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// searchCacheKey normalizes a /search/ request into a cache key: every
+// query param that affects the marketplace filter, sorted, plus the
+// resolved count. "cache", "format" and "count" are excluded from the
+// sorted params since they either don't affect the result (cache, format)
+// or are appended separately below (count, which may be defaulted rather
+// than taken verbatim from the query string).
+func searchCacheKey(values url.Values, count uint64) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		if k == "cache" || k == "format" || k == "count" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s&", k, values.Get(k))
+	}
+	fmt.Fprintf(&b, "count=%d", count)
+
+	return b.String()
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"log"
+	"math/big"
+	"net/http"
+	"sort"
+
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+	pb "github.com/sonm-io/core/proto"
+)
+
+// matchedPair is one crossed ASK/BID pair found while walking the two
+// sorted order books.
+type matchedPair struct {
+	Ask *row `json:"ask"`
+	Bid *row `json:"bid"`
+}
+
+// depthSummary carries the top-of-book and liquidity figures shown above
+// the /depth/ order book table.
+type depthSummary struct {
+	BestAsk        string `json:"best_ask"`
+	BestBid        string `json:"best_bid"`
+	Spread         string `json:"spread"`
+	MatchableCount int    `json:"matchable_count"`
+	MatchedVolume  string `json:"matched_volume"`
+}
+
+// slotSatisfies reports whether offer (an ASK's slot) covers every resource
+// dimension demand (a BID's slot) requires: equal or more CPU, RAM, GPU,
+// storage and network bandwidth, plus a compatible network type. It is
+// factored out so the matching rule can be unit-tested without a
+// marketplace client.
+func slotSatisfies(offer, demand *pb.Slot) bool {
+	offerRes := offer.GetResources()
+	demandRes := demand.GetResources()
+
+	switch {
+	case offerRes.GetCpuCores() < demandRes.GetCpuCores():
+		return false
+	case offerRes.GetRamBytes() < demandRes.GetRamBytes():
+		return false
+	case offerRes.GetGpuCount() < demandRes.GetGpuCount():
+		return false
+	case offerRes.GetStorage() < demandRes.GetStorage():
+		return false
+	case offerRes.GetNetTrafficIn() < demandRes.GetNetTrafficIn():
+		return false
+	case offerRes.GetNetTrafficOut() < demandRes.GetNetTrafficOut():
+		return false
+	case !networkTypeSatisfies(offerRes.GetNetworkType(), demandRes.GetNetworkType()):
+		return false
+	default:
+		return true
+	}
+}
+
+// networkTypeSatisfies reports whether offer provides every network
+// direction demand requires. NetworkType is a direction bitmask
+// (INCOMING=1, OUTBOUND=2, INCOMING_AND_OUTBOUND=3), not a linear
+// capability scale, so an OUTBOUND-only offer must not satisfy an
+// INCOMING-only demand even though 2 > 1.
+func networkTypeSatisfies(offer, demand pb.NetworkType) bool {
+	return demand&offer == demand
+}
+
+// priceCmp compares two decimal order prices, as found in pb.Order.Price.
+func priceCmp(a, b string) (int, error) {
+	pa, err := pb.NewBigIntFromString(a)
+	if err != nil {
+		return 0, errors.Wrapf(err, "cannot parse price %q", a)
+	}
+
+	pbv, err := pb.NewBigIntFromString(b)
+	if err != nil {
+		return 0, errors.Wrapf(err, "cannot parse price %q", b)
+	}
+
+	return pa.Unwrap().Cmp(pbv.Unwrap()), nil
+}
+
+// matchOrders walks asks (ascending by price) against bids (descending by
+// price), pairing each ask with the best-priced remaining bid that crosses
+// it and whose slot it can satisfy. It returns the matched pairs plus the
+// residual, unmatched order books.
+func matchOrders(asks, bids []*pb.Order) ([]matchedPair, []*pb.Order, []*pb.Order) {
+	bidUsed := make([]bool, len(bids))
+	var pairs []matchedPair
+	var restAsks []*pb.Order
+
+	for _, ask := range asks {
+		matched := false
+
+		for bi, bid := range bids {
+			if bidUsed[bi] {
+				continue
+			}
+
+			cmp, err := priceCmp(bid.GetPrice(), ask.GetPrice())
+			if err != nil || cmp < 0 {
+				continue
+			}
+
+			if !slotSatisfies(ask.GetSlot(), bid.GetSlot()) {
+				continue
+			}
+
+			pairs = append(pairs, matchedPair{Ask: orderToRow(ask), Bid: orderToRow(bid)})
+			bidUsed[bi] = true
+			matched = true
+			break
+		}
+
+		if !matched {
+			restAsks = append(restAsks, ask)
+		}
+	}
+
+	var restBids []*pb.Order
+	for bi, bid := range bids {
+		if !bidUsed[bi] {
+			restBids = append(restBids, bid)
+		}
+	}
+
+	return pairs, restAsks, restBids
+}
+
+// summarizeDepth computes the best bid/ask, spread, and total matched
+// volume (the sum of each matched pair's ask price) from the (already
+// sorted) order books.
+func summarizeDepth(asks, bids []*pb.Order, pairs []matchedPair) depthSummary {
+	s := depthSummary{MatchableCount: len(pairs)}
+
+	volume := big.NewInt(0)
+	for _, pair := range pairs {
+		price, err := pb.NewBigIntFromString(pair.Ask.Price)
+		if err != nil {
+			continue
+		}
+		volume.Add(volume, price.Unwrap())
+	}
+	s.MatchedVolume = volume.String()
+
+	if len(asks) > 0 {
+		s.BestAsk = asks[0].GetPrice()
+	}
+	if len(bids) > 0 {
+		s.BestBid = bids[0].GetPrice()
+	}
+
+	if s.BestAsk == "" || s.BestBid == "" {
+		return s
+	}
+
+	ask, err := pb.NewBigIntFromString(s.BestAsk)
+	if err != nil {
+		return s
+	}
+	bid, err := pb.NewBigIntFromString(s.BestBid)
+	if err != nil {
+		return s
+	}
+
+	s.Spread = new(big.Int).Sub(ask.Unwrap(), bid.Unwrap()).String()
+	return s
+}
+
+func rowsOf(orders []*pb.Order) []*row {
+	out := make([]*row, 0, len(orders))
+	for _, o := range orders {
+		out = append(out, orderToRow(o))
+	}
+	return out
+}
+
+// fetchSide retrieves up to 1000 orders of the given type matching the
+// resource profile in base.
+func fetchSide(app *App, base *pb.Order, t pb.OrderType) ([]*pb.Order, error) {
+	req := &pb.GetOrdersRequest{
+		Order: &pb.Order{
+			PricePerSecond: base.GetPricePerSecond(),
+			OrderType:      t,
+			Slot:           base.GetSlot(),
+		},
+		Count: 1000,
+	}
+
+	resp, err := app.market.GetOrders(app.ctx, req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot retrieve %s orders", t)
+	}
+
+	return resp.GetOrders(), nil
+}
+
+// registerDepthRoutes mounts the /depth/ market-depth view: an HTML page at
+// /depth plus its JSON data source at /depth/.
+func registerDepthRoutes(e *echo.Echo, app *App) {
+	e.GET("/depth", func(c echo.Context) error {
+		log.Println("handling depth page request")
+		return c.Render(http.StatusOK, "depth.html", nil)
+	})
+
+	e.GET("/depth/", func(c echo.Context) error {
+		log.Println("handling depth request")
+
+		base, err := reqContextToOrder(c)
+		if err != nil {
+			log.Printf("cannot convert request to order: %v\r\n", err)
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		asks, err := fetchSide(app, base, pb.OrderType_ASK)
+		if err != nil {
+			log.Printf("cannot retrieve asks: %v\r\n", err)
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		bids, err := fetchSide(app, base, pb.OrderType_BID)
+		if err != nil {
+			log.Printf("cannot retrieve bids: %v\r\n", err)
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		sort.Slice(asks, func(i, j int) bool {
+			cmp, err := priceCmp(asks[i].GetPrice(), asks[j].GetPrice())
+			return err == nil && cmp < 0
+		})
+		sort.Slice(bids, func(i, j int) bool {
+			cmp, err := priceCmp(bids[i].GetPrice(), bids[j].GetPrice())
+			return err == nil && cmp > 0
+		})
+
+		pairs, restAsks, restBids := matchOrders(asks, bids)
+
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"matched": pairs,
+			"asks":    rowsOf(restAsks),
+			"bids":    rowsOf(restBids),
+			"summary": summarizeDepth(asks, bids, pairs),
+		})
+	})
+}
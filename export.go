@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// csvHeader matches the json tag order of the row struct, so CSV output
+// lines up with the field order used elsewhere in the browser.
+var csvHeader = []string{
+	"id", "client_id", "order_type", "price", "duration",
+	"cpu", "gpu", "ram", "net_type", "net_in", "net_out",
+}
+
+// writeRowsCSV writes rows as RFC-4180 CSV, quoting handled by
+// encoding/csv.
+func writeRowsCSV(w io.Writer, rows []*row) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, r := range rows {
+		record := []string{
+			r.ID, r.ClientID, r.OrderType, r.Price, r.Duration,
+			r.CPU, r.GPU, r.RAM, r.NetType, r.NetIn, r.NetOut,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeRowsNDJSON writes rows as newline-delimited JSON, one row per line.
+func writeRowsNDJSON(w io.Writer, rows []*row) error {
+	enc := json.NewEncoder(w)
+	for _, r := range rows {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
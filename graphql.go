@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	ds "github.com/c2h5oh/datasize"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/handler"
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+	pb "github.com/sonm-io/core/proto"
+)
+
+// bigIntScalar carries SONM's arbitrary-precision price/volume values over
+// the wire as decimal strings, mirroring how pb.BigInt already serializes
+// to JSON elsewhere in this browser.
+var bigIntScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "BigInt",
+	Description: "an arbitrary-precision decimal number, encoded as a string",
+	Serialize: func(value interface{}) interface{} {
+		return fmt.Sprintf("%v", value)
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return fmt.Sprintf("%v", value)
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		if v, ok := valueAST.(*ast.StringValue); ok {
+			return v.Value
+		}
+		return nil
+	},
+})
+
+var orderTypeEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "OrderType",
+	Values: graphql.EnumValueConfigMap{
+		"ASK": &graphql.EnumValueConfig{Value: pb.OrderType_ASK},
+		"BID": &graphql.EnumValueConfig{Value: pb.OrderType_BID},
+	},
+})
+
+var networkTypeEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "NetworkType",
+	Values: graphql.EnumValueConfigMap{
+		"NO_NETWORK":            &graphql.EnumValueConfig{Value: pb.NetworkType_NO_NETWORK},
+		"INCOMING":              &graphql.EnumValueConfig{Value: pb.NetworkType_INCOMING},
+		"OUTBOUND":              &graphql.EnumValueConfig{Value: pb.NetworkType_OUTBOUND},
+		"INCOMING_AND_OUTBOUND": &graphql.EnumValueConfig{Value: pb.NetworkType_INCOMING_AND_OUTBOUND},
+	},
+})
+
+var resourcesObjectType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Resources",
+	Fields: graphql.Fields{
+		"cpuCores": &graphql.Field{
+			Type: graphql.Int,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*pb.Resources).GetCpuCores(), nil
+			},
+		},
+		"ramBytes": &graphql.Field{
+			Type: graphql.Float,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*pb.Resources).GetRamBytes(), nil
+			},
+		},
+		"gpuCount": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*pb.Resources).GetGpuCount().String(), nil
+			},
+		},
+		"storage": &graphql.Field{
+			Type: graphql.Float,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*pb.Resources).GetStorage(), nil
+			},
+		},
+		"networkType": &graphql.Field{
+			Type: networkTypeEnum,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*pb.Resources).GetNetworkType(), nil
+			},
+		},
+	},
+})
+
+var slotObjectType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Slot",
+	Fields: graphql.Fields{
+		"duration": &graphql.Field{
+			Type: graphql.Int,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*orderProjection).Duration, nil
+			},
+		},
+		"resources": &graphql.Field{
+			Type: resourcesObjectType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*orderProjection).Resources, nil
+			},
+		},
+	},
+})
+
+var orderObjectType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Order",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*orderProjection).ID, nil
+			},
+		},
+		"supplierId": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*orderProjection).ClientID, nil
+			},
+		},
+		"orderType": &graphql.Field{
+			Type: orderTypeEnum,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*orderProjection).OrderType, nil
+			},
+		},
+		"price": &graphql.Field{
+			Type: bigIntScalar,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*orderProjection).Price, nil
+			},
+		},
+		"slot": &graphql.Field{
+			Type: slotObjectType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				// Slot and Resources are both projected off the same
+				// orderProjection, so just pass the parent through.
+				return p.Source, nil
+			},
+		},
+	},
+})
+
+var orderFilterInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "OrderFilterInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"type":              &graphql.InputObjectFieldConfig{Type: orderTypeEnum},
+		"cpuCoresMin":       &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"ramBytesMax":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"pricePerSecondMax": &graphql.InputObjectFieldConfig{Type: graphql.String},
+	},
+})
+
+// orderFilterLimits holds the upper-bound constraints a queryOrders filter
+// can ask for that the marketplace's own GetOrders filter has no way to
+// express (it only matches resource fields as a floor, the same way the
+// REST /search/ handler's cpu/ram/etc. params do). queryOrders applies
+// these to the fetched results itself instead of sending them upstream.
+type orderFilterLimits struct {
+	ramBytesMax       *uint64
+	pricePerSecondMax *string
+}
+
+// satisfiedBy reports whether an order fetched from the marketplace obeys
+// the upper bounds requested in the filter.
+func (l *orderFilterLimits) satisfiedBy(o *pb.Order) bool {
+	if l.ramBytesMax != nil && o.GetSlot().GetResources().GetRamBytes() > *l.ramBytesMax {
+		return false
+	}
+
+	if l.pricePerSecondMax != nil {
+		cmp, err := priceCmp(o.GetPrice(), *l.pricePerSecondMax)
+		if err != nil || cmp > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterToOrder translates a queryOrders filter argument into the pb.Order
+// the marketplace client's GetOrders expects, the same role reqContextToOrder
+// plays for the REST /search/ handler, plus the limits GetOrders itself
+// cannot enforce.
+func filterToOrder(filter map[string]interface{}) (*pb.Order, *orderFilterLimits, error) {
+	order := &pb.Order{
+		PricePerSecond: pb.NewBigIntFromInt(1),
+		Slot:           &pb.Slot{Resources: &pb.Resources{}},
+	}
+	limits := &orderFilterLimits{}
+
+	if filter == nil {
+		return order, limits, nil
+	}
+
+	if v, ok := filter["type"]; ok {
+		order.OrderType = v.(pb.OrderType)
+	}
+
+	if v, ok := filter["cpuCoresMin"].(int); ok {
+		order.Slot.Resources.CpuCores = uint64(v)
+	}
+
+	if v, ok := filter["ramBytesMax"].(string); ok && v != "" {
+		var bs ds.ByteSize
+		if err := bs.UnmarshalText([]byte(v)); err != nil {
+			return nil, nil, errors.Wrapf(err, "cannot parse ramBytesMax %q", v)
+		}
+		max := uint64(bs.Bytes())
+		limits.ramBytesMax = &max
+	}
+
+	if v, ok := filter["pricePerSecondMax"].(string); ok && v != "" {
+		price, err := pb.NewBigIntFromString(v)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "cannot parse pricePerSecondMax")
+		}
+		max := price.Unwrap().String()
+		limits.pricePerSecondMax = &max
+	}
+
+	return order, limits, nil
+}
+
+// newSchema builds the GraphQL schema exposed at /graphql, backed by the
+// same marketplace client the REST handlers use.
+func newSchema(app *App) graphql.Schema {
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"queryOrders": &graphql.Field{
+				Type: graphql.NewList(orderObjectType),
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: orderFilterInput},
+					"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 25},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					filter, _ := p.Args["filter"].(map[string]interface{})
+					order, limits, err := filterToOrder(filter)
+					if err != nil {
+						return nil, errors.Wrap(err, "invalid filter")
+					}
+
+					limit, _ := p.Args["limit"].(int)
+					req := &pb.GetOrdersRequest{Order: order, Count: uint64(limit)}
+
+					resp, err := app.market.GetOrders(app.ctx, req)
+					if err != nil {
+						return nil, errors.Wrap(err, "cannot retrieve orders from marketplace")
+					}
+
+					out := make([]*orderProjection, 0, len(resp.GetOrders()))
+					for _, o := range resp.GetOrders() {
+						if !limits.satisfiedBy(o) {
+							continue
+						}
+						out = append(out, projectOrder(o))
+					}
+					return out, nil
+				},
+			},
+			"getOrderById": &graphql.Field{
+				Type: orderObjectType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, _ := p.Args["id"].(string)
+
+					o, err := app.market.GetOrderByID(app.ctx, &pb.ID{Id: id})
+					if err != nil {
+						return nil, errors.Wrapf(err, "cannot retrieve order %q", id)
+					}
+
+					return projectOrder(o), nil
+				},
+			},
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+	if err != nil {
+		// the schema is built from a static literal, so a failure here is a
+		// programming mistake, not a runtime condition callers recover from.
+		panic(errors.Wrap(err, "cannot build GraphQL schema"))
+	}
+
+	return schema
+}
+
+// registerGraphQLRoutes mounts the GraphQL API, and optionally the
+// GraphQL Playground, on e.
+func registerGraphQLRoutes(e *echo.Echo, app *App, enablePlayground bool) {
+	schema := newSchema(app)
+
+	h := handler.New(&handler.Config{Schema: &schema, Pretty: true})
+	e.Any("/graphql", echo.WrapHandler(h))
+
+	if enablePlayground {
+		log.Println("GraphQL Playground enabled at /graphql/playground")
+		p := handler.New(&handler.Config{Schema: &schema, Pretty: true, GraphiQL: true})
+		e.Any("/graphql/playground", echo.WrapHandler(p))
+	}
+}
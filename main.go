@@ -2,7 +2,8 @@ package main
 
 import (
 	"crypto"
-	"fmt"
+	"crypto/ecdsa"
+	"flag"
 	"html/template"
 	"io"
 	"log"
@@ -11,8 +12,6 @@ import (
 	"strconv"
 	"time"
 
-	ds "github.com/c2h5oh/datasize"
-	ethc "github.com/ethereum/go-ethereum/crypto"
 	"github.com/labstack/echo"
 	"github.com/pkg/errors"
 	"github.com/sonm-io/core/insonmnia/structs"
@@ -34,13 +33,13 @@ type App struct {
 	market pb.MarketClient
 }
 
-func newApp(ctx context.Context) (*App, error) {
-	key, err := ethc.GenerateKey()
-	if err != nil {
-		return nil, err
+func newApp(ctx context.Context, key crypto.PrivateKey) (*App, error) {
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("wallet key is not an ECDSA private key")
 	}
 
-	_, TLSConfig, err := util.NewHitlessCertRotator(ctx, key)
+	_, TLSConfig, err := util.NewHitlessCertRotator(ctx, ecKey)
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +52,7 @@ func newApp(ctx context.Context) (*App, error) {
 
 	return &App{
 		ctx:    ctx,
-		key:    key,
+		key:    ecKey,
 		market: pb.NewMarketClient(cc),
 	}, nil
 }
@@ -74,11 +73,33 @@ func (t *TemplateRenderer) Render(w io.Writer, name string, data interface{}, c
 	return t.templates.ExecuteTemplate(w, name, data)
 }
 
+var (
+	gqlPlayground  = flag.Bool("gql-playground", false, "serve GraphQL Playground at /graphql/playground")
+	keystorePath   = flag.String("keystore", defaultKeystorePath(), "path to the encrypted keystore file")
+	wsPollPeriod   = flag.Duration("ws-poll-interval", 5*time.Second, "how often /ws/orders polls the marketplace for changes")
+	searchCacheTTL = flag.Duration("search-cache-ttl", 10*time.Second, "how long /search/ results are cached before re-querying the marketplace")
+)
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "wallet" {
+		if err := runWalletCommand(os.Args[2:]); err != nil {
+			log.Printf("wallet command failed: %v\r\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	flag.Parse()
 	ctx := context.Background()
 
 	log.Println("Starting remotes...")
-	app, err := newApp(ctx)
+	key, err := loadOrCreateWallet(*keystorePath)
+	if err != nil {
+		log.Printf("Cannot load wallet: %v\r\n", err)
+		os.Exit(1)
+	}
+
+	app, err := newApp(ctx, key)
 	if err != nil {
 		log.Printf("Cannot create app: %v\r\n", err)
 		os.Exit(1)
@@ -89,6 +110,8 @@ func main() {
 		templates: template.Must(template.ParseGlob("./static/*.html")),
 	}
 
+	cache := newSearchCache(*searchCacheTTL)
+
 	e.GET("/", func(c echo.Context) error {
 		log.Println("handling index request")
 		return c.Render(http.StatusOK, "index.html", nil)
@@ -107,25 +130,56 @@ func main() {
 			count = 25
 		}
 
-		marketReq := &pb.GetOrdersRequest{
-			Order: order,
-			Count: count,
-		}
+		bypassCache := c.QueryParam("cache") == "no"
+		key := searchCacheKey(c.QueryParams(), count)
+		cacheStatus := "MISS"
 
-		orders, err := app.market.GetOrders(ctx, marketReq)
-		if err != nil {
-			log.Printf("cannot retrieve orders from Maretplace: %v\r\n", err)
-			return c.String(http.StatusBadRequest, err.Error())
+		orders, hit := []*pb.Order(nil), false
+		if !bypassCache {
+			orders, hit = cache.get(key)
 		}
 
-		data := make([]*row, 0, len(orders.GetOrders()))
-		for _, item := range orders.GetOrders() {
-			data = append(data, orderToRow(item))
+		if hit {
+			cacheStatus = "HIT"
+		} else {
+			marketReq := &pb.GetOrdersRequest{
+				Order: order,
+				Count: count,
+			}
+
+			resp, err := app.market.GetOrders(ctx, marketReq)
+			if err != nil {
+				log.Printf("cannot retrieve orders from Maretplace: %v\r\n", err)
+				return c.String(http.StatusBadRequest, err.Error())
+			}
+
+			orders = resp.GetOrders()
+			if !bypassCache {
+				cache.set(key, orders)
+			}
 		}
 
-		return c.JSON(http.StatusOK, map[string]interface{}{"data": data})
+		c.Response().Header().Set("X-Cache", cacheStatus)
+
+		data := rowsOf(orders)
+
+		switch c.QueryParam("format") {
+		case "csv":
+			c.Response().Header().Set(echo.HeaderContentType, "text/csv")
+			return writeRowsCSV(c.Response(), data)
+		case "ndjson":
+			c.Response().Header().Set(echo.HeaderContentType, "application/x-ndjson")
+			return writeRowsNDJSON(c.Response(), data)
+		default:
+			return c.JSON(http.StatusOK, map[string]interface{}{"data": data})
+		}
 	})
 
+	registerGraphQLRoutes(e, app, *gqlPlayground)
+	registerOrderWriteRoutes(e, app)
+	registerOrderStreamRoutes(e, app, *wsPollPeriod)
+	registerDepthRoutes(e, app)
+
 	log.Println("Starting web server...")
 	err = e.Start(":8087")
 	if err != nil {
@@ -134,86 +188,16 @@ func main() {
 	}
 }
 
+// reqContextToOrder parses the query-string filter fields off an Echo
+// request into a pb.Order and validates it. Parsing itself lives in
+// parseOrderParams so it can be exercised without spinning up Echo.
 func reqContextToOrder(c echo.Context) (*pb.Order, error) {
-	clientID := c.QueryParam("client_id")
-	pricePerSec := c.QueryParam("pps")
-	price, err := pb.NewBigIntFromString(pricePerSec)
+	order, err := parseOrderParams(c.QueryParams())
 	if err != nil {
 		return nil, err
 	}
 
-	orderType, err := strconv.ParseInt(c.QueryParam("type"), 10, 64)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot convert order type")
-	}
-
-	duration, err := strconv.ParseInt(c.QueryParam("duration"), 10, 64)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot convert order duration")
-	}
-
-	cpu, err := strconv.ParseUint(c.QueryParam("cpu"), 10, 64)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot convert order cpu")
-	}
-
-	gpu, err := strconv.ParseInt(c.QueryParam("gpu"), 10, 64)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot convert order gpu")
-	}
-
-	ram, err := strconv.ParseUint(c.QueryParam("ram"), 10, 64)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot convert order ram")
-	}
-
-	storage, err := strconv.ParseUint(c.QueryParam("storage"), 10, 64)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot convert order storage")
-	}
-
-	netType, err := strconv.ParseInt(c.QueryParam("net_type"), 10, 64)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot convert order net_type")
-	}
-
-	netIn, err := strconv.ParseUint(c.QueryParam("net_in"), 10, 64)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot convert order net_in")
-	}
-
-	netOut, err := strconv.ParseUint(c.QueryParam("net_out"), 10, 64)
-	if err != nil {
-		return nil, errors.Wrap(err, "cannot convert order net_out")
-	}
-
-	order := &pb.Order{
-		// price field is temporary hacked
-		PricePerSecond: pb.NewBigIntFromInt(1),
-		Price:          price.Unwrap().String(),
-		OrderType:      pb.OrderType(orderType),
-		Slot: &pb.Slot{
-			Duration: uint64(duration),
-			Resources: &pb.Resources{
-				CpuCores:      cpu,
-				RamBytes:      ram,
-				GpuCount:      pb.GPUCount(gpu),
-				Storage:       storage,
-				NetworkType:   pb.NetworkType(netType),
-				NetTrafficIn:  netIn,
-				NetTrafficOut: netOut,
-			},
-		},
-	}
-
-	if order.OrderType == pb.OrderType_ASK {
-		order.SupplierID = clientID
-	} else {
-		order.ByuerID = clientID
-	}
-
-	_, err = structs.NewOrder(order)
-	if err != nil {
+	if _, err := structs.NewOrder(order); err != nil {
 		return nil, errors.Wrap(err, "order is malformed")
 	}
 
@@ -234,28 +218,3 @@ type row struct {
 	NetIn     string `json:"net_in"`
 	NetOut    string `json:"net_out"`
 }
-
-// orderToRow converts found order to row representation
-func orderToRow(o *pb.Order) *row {
-	r := &row{
-		ID:        o.GetId(),
-		OrderType: o.OrderType.String(),
-		// Price:     o.PricePerSecond.Unwrap().String(),
-		Price:    o.GetPrice(),
-		Duration: time.Duration(time.Duration(o.GetSlot().GetDuration()) * time.Second).String(),
-		CPU:      fmt.Sprintf("%d", o.GetSlot().GetResources().GetCpuCores()),
-		GPU:      o.GetSlot().GetResources().GetGpuCount().String(),
-		NetType:  o.GetSlot().GetResources().GetNetworkType().String(),
-		RAM:      ds.ByteSize(o.GetSlot().GetResources().GetRamBytes()).HR(),
-		NetIn:    ds.ByteSize(o.GetSlot().GetResources().GetNetTrafficIn()).HR(),
-		NetOut:   ds.ByteSize(o.GetSlot().GetResources().GetNetTrafficOut()).HR(),
-	}
-
-	if o.GetOrderType() == pb.OrderType_ASK {
-		r.ClientID = o.GetSupplierID()
-	} else {
-		r.ClientID = o.GetByuerID()
-	}
-
-	return r
-}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/sonm-io/core/insonmnia/structs"
+)
+
+func validOrderParams() url.Values {
+	return url.Values{
+		"client_id": {"0xabc"},
+		"pps":       {"1"},
+		"type":      {"1"},
+		"duration":  {"3600"},
+		"cpu":       {"4"},
+		"gpu":       {"0"},
+		"ram":       {"1024"},
+		"storage":   {"1024"},
+		"net_type":  {"0"},
+		"net_in":    {"0"},
+		"net_out":   {"0"},
+	}
+}
+
+func TestParseOrderParamsValid(t *testing.T) {
+	order, err := parseOrderParams(validOrderParams())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := order.GetSlot().GetResources().GetCpuCores(); got != 4 {
+		t.Fatalf("unexpected cpu cores: %d", got)
+	}
+}
+
+func TestParseOrderParamsFieldErrors(t *testing.T) {
+	fields := []string{"pps", "type", "duration", "cpu", "gpu", "ram", "storage", "net_type", "net_in", "net_out"}
+
+	for _, field := range fields {
+		field := field
+		t.Run(field, func(t *testing.T) {
+			params := validOrderParams()
+			params.Set(field, "not-a-number")
+
+			if _, err := parseOrderParams(params); err == nil {
+				t.Fatalf("expected an error for invalid %s", field)
+			}
+		})
+	}
+}
+
+// FuzzReqContextToOrder exercises parseOrderParams, the pure function
+// backing reqContextToOrder, against adversarial query strings. The
+// contract under test is "returns a non-nil error, or an order that
+// round-trips through structs.NewOrder without panicking" — a
+// parseable-but-invalid order (e.g. an out-of-range OrderType) is a
+// legitimate, non-panicking rejection from structs.NewOrder, not a fuzz
+// failure, so only a panic fails the test.
+func FuzzReqContextToOrder(f *testing.F) {
+	seeds := []string{
+		"client_id=0xabc&pps=1&type=1&duration=3600&cpu=4&gpu=0&ram=1024&storage=1024&net_type=0&net_in=0&net_out=0",
+		"",
+		"client_id=&pps=-1&type=-1&duration=-1&cpu=0&gpu=-1&ram=0&storage=0&net_type=0&net_in=0&net_out=0",
+		"pps=99999999999999999999999999999999999999999999999999&type=0&duration=0&cpu=0&gpu=0&ram=0&storage=0&net_type=0&net_in=0&net_out=0",
+		"client_id=%E2%98%83&pps=1&type=1&duration=1&cpu=1&gpu=1&ram=1&storage=1&net_type=1&net_in=1&net_out=1",
+	}
+	for _, s := range seeds {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, raw string) {
+		v, err := url.ParseQuery(raw)
+		if err != nil {
+			t.Skip("not a valid query string")
+		}
+
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("parseOrderParams/structs.NewOrder panicked: %v", r)
+			}
+		}()
+
+		order, err := parseOrderParams(v)
+		if err != nil {
+			return
+		}
+
+		if order == nil {
+			t.Fatal("parseOrderParams returned a nil order without an error")
+		}
+
+		// A rejection here is a legitimate outcome (invalid resource
+		// values, out-of-range enums, ...); only a panic is a bug.
+		_, _ = structs.NewOrder(order)
+	})
+}
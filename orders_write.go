@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	ds "github.com/c2h5oh/datasize"
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+	"github.com/sonm-io/core/insonmnia/structs"
+	pb "github.com/sonm-io/core/proto"
+)
+
+// createOrderRequest is the JSON body accepted by POST /orders. Its fields
+// mirror row's shape exactly (order_type as "ASK"/"BID", duration as a
+// time.Duration string, cpu as a plain decimal string, ram/net_in/net_out
+// as human-readable byte sizes) so a row read back from /search/ can be
+// posted here unmodified, plus the price the client is willing to pay or
+// charge per second.
+type createOrderRequest struct {
+	ClientID       string `json:"client_id"`
+	OrderType      string `json:"order_type"`
+	Duration       string `json:"duration"`
+	CPU            string `json:"cpu"`
+	GPU            string `json:"gpu"`
+	RAM            string `json:"ram"`
+	NetType        string `json:"net_type"`
+	NetIn          string `json:"net_in"`
+	NetOut         string `json:"net_out"`
+	Price          string `json:"price"`
+	PricePerSecond string `json:"price_per_second"`
+}
+
+// parseByteSize parses a human-readable byte size, the same "1.0 GB" shape
+// ds.ByteSize.HR produces in row, back into a raw byte count.
+func parseByteSize(v string) (uint64, error) {
+	var bs ds.ByteSize
+	if err := bs.UnmarshalText([]byte(v)); err == nil {
+		return uint64(bs.Bytes()), nil
+	}
+
+	// UnmarshalText only takes whole numbers, while HR renders one decimal
+	// place, so split "1.5 GB" into its number and unit and scale by hand.
+	var num float64
+	var unit string
+	if _, err := fmt.Sscanf(v, "%f %s", &num, &unit); err != nil {
+		return 0, err
+	}
+	if err := bs.UnmarshalText([]byte("1 " + unit)); err != nil {
+		return 0, err
+	}
+	return uint64(num * float64(bs.Bytes())), nil
+}
+
+// toPbOrder validates the request and converts it to the pb.Order the
+// marketplace client expects, the same validation reqContextToOrder
+// performs for the read-only /search/ handler.
+func (r *createOrderRequest) toPbOrder() (*pb.Order, error) {
+	price, err := pb.NewBigIntFromString(r.Price)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert order price")
+	}
+
+	pps, err := pb.NewBigIntFromString(r.PricePerSecond)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert price_per_second")
+	}
+
+	orderType, ok := pb.OrderType_value[r.OrderType]
+	if !ok {
+		return nil, errors.Errorf("unknown order_type %q", r.OrderType)
+	}
+
+	duration, err := time.ParseDuration(r.Duration)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert order duration")
+	}
+
+	cpu, err := strconv.ParseUint(r.CPU, 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert order cpu")
+	}
+
+	gpu, ok := pb.GPUCount_value[r.GPU]
+	if !ok {
+		return nil, errors.Errorf("unknown gpu %q", r.GPU)
+	}
+
+	ram, err := parseByteSize(r.RAM)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert order ram")
+	}
+
+	netType, ok := pb.NetworkType_value[r.NetType]
+	if !ok {
+		return nil, errors.Errorf("unknown net_type %q", r.NetType)
+	}
+
+	netIn, err := parseByteSize(r.NetIn)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert order net_in")
+	}
+
+	netOut, err := parseByteSize(r.NetOut)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert order net_out")
+	}
+
+	order := &pb.Order{
+		PricePerSecond: pps,
+		Price:          price.Unwrap().String(),
+		OrderType:      pb.OrderType(orderType),
+		Slot: &pb.Slot{
+			Duration: uint64(duration.Seconds()),
+			Resources: &pb.Resources{
+				CpuCores:      cpu,
+				RamBytes:      ram,
+				GpuCount:      pb.GPUCount(gpu),
+				NetworkType:   pb.NetworkType(netType),
+				NetTrafficIn:  netIn,
+				NetTrafficOut: netOut,
+			},
+		},
+	}
+
+	if order.OrderType == pb.OrderType_ASK {
+		order.SupplierID = r.ClientID
+	} else {
+		order.ByuerID = r.ClientID
+	}
+
+	if _, err := structs.NewOrder(order); err != nil {
+		return nil, errors.Wrap(err, "order is malformed")
+	}
+
+	return order, nil
+}
+
+// registerOrderWriteRoutes mounts the authenticated order-placement
+// endpoints. Both requests are signed with the App's persisted wallet key
+// via the already wallet-authenticated market client.
+func registerOrderWriteRoutes(e *echo.Echo, app *App) {
+	e.POST("/orders", func(c echo.Context) error {
+		log.Println("handling create order request")
+
+		req := &createOrderRequest{}
+		if err := c.Bind(req); err != nil {
+			log.Printf("cannot bind create order request: %v\r\n", err)
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		order, err := req.toPbOrder()
+		if err != nil {
+			log.Printf("cannot convert request to order: %v\r\n", err)
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		created, err := app.market.CreateOrder(app.ctx, order)
+		if err != nil {
+			log.Printf("cannot create order: %v\r\n", err)
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		return c.JSON(http.StatusOK, map[string]interface{}{"id": created.GetId()})
+	})
+
+	e.DELETE("/orders/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		log.Printf("handling cancel order request: %s\r\n", id)
+
+		if _, err := app.market.CancelOrder(app.ctx, &pb.Order{Id: id}); err != nil {
+			log.Printf("cannot cancel order %q: %v\r\n", id, err)
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+	pb "github.com/sonm-io/core/proto"
+)
+
+// parseOrderParams parses the filter fields reqContextToOrder accepts off
+// raw query-string values into a pb.Order. It is kept free of Echo, and of
+// structs.NewOrder validation, so it can be called directly from tests
+// (and FuzzReqContextToOrder) without spinning up a request, and so that
+// callers can independently assert the result validates.
+func parseOrderParams(v url.Values) (*pb.Order, error) {
+	clientID := v.Get("client_id")
+
+	price, err := pb.NewBigIntFromString(v.Get("pps"))
+	if err != nil {
+		return nil, err
+	}
+
+	orderType, err := strconv.ParseInt(v.Get("type"), 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert order type")
+	}
+
+	duration, err := strconv.ParseInt(v.Get("duration"), 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert order duration")
+	}
+
+	cpu, err := strconv.ParseUint(v.Get("cpu"), 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert order cpu")
+	}
+
+	gpu, err := strconv.ParseInt(v.Get("gpu"), 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert order gpu")
+	}
+
+	ram, err := strconv.ParseUint(v.Get("ram"), 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert order ram")
+	}
+
+	storage, err := strconv.ParseUint(v.Get("storage"), 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert order storage")
+	}
+
+	netType, err := strconv.ParseInt(v.Get("net_type"), 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert order net_type")
+	}
+
+	netIn, err := strconv.ParseUint(v.Get("net_in"), 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert order net_in")
+	}
+
+	netOut, err := strconv.ParseUint(v.Get("net_out"), 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert order net_out")
+	}
+
+	order := &pb.Order{
+		// price field is temporary hacked
+		PricePerSecond: pb.NewBigIntFromInt(1),
+		Price:          price.Unwrap().String(),
+		OrderType:      pb.OrderType(orderType),
+		Slot: &pb.Slot{
+			Duration: uint64(duration),
+			Resources: &pb.Resources{
+				CpuCores:      cpu,
+				RamBytes:      ram,
+				GpuCount:      pb.GPUCount(gpu),
+				Storage:       storage,
+				NetworkType:   pb.NetworkType(netType),
+				NetTrafficIn:  netIn,
+				NetTrafficOut: netOut,
+			},
+		},
+	}
+
+	if order.OrderType == pb.OrderType_ASK {
+		order.SupplierID = clientID
+	} else {
+		order.ByuerID = clientID
+	}
+
+	return order, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	ds "github.com/c2h5oh/datasize"
+	pb "github.com/sonm-io/core/proto"
+)
+
+// orderProjection is the common, typed view of a pb.Order that both the
+// REST /search/ handler and the GraphQL resolvers render their own
+// representations from, so the two APIs never drift apart on field mapping.
+type orderProjection struct {
+	ID        string
+	ClientID  string
+	OrderType pb.OrderType
+	Price     string
+	Duration  uint64
+	Resources *pb.Resources
+}
+
+// projectOrder extracts the fields the browser cares about from a raw
+// marketplace order.
+func projectOrder(o *pb.Order) *orderProjection {
+	p := &orderProjection{
+		ID:        o.GetId(),
+		OrderType: o.GetOrderType(),
+		Price:     o.GetPrice(),
+		Duration:  o.GetSlot().GetDuration(),
+		Resources: o.GetSlot().GetResources(),
+	}
+
+	if o.GetOrderType() == pb.OrderType_ASK {
+		p.ClientID = o.GetSupplierID()
+	} else {
+		p.ClientID = o.GetByuerID()
+	}
+
+	return p
+}
+
+// orderToRow converts found order to row representation
+func orderToRow(o *pb.Order) *row {
+	p := projectOrder(o)
+	res := p.Resources
+
+	return &row{
+		ID:        p.ID,
+		ClientID:  p.ClientID,
+		OrderType: p.OrderType.String(),
+		Price:     p.Price,
+		Duration:  time.Duration(time.Duration(p.Duration) * time.Second).String(),
+		CPU:       fmt.Sprintf("%d", res.GetCpuCores()),
+		GPU:       res.GetGpuCount().String(),
+		NetType:   res.GetNetworkType().String(),
+		RAM:       ds.ByteSize(res.GetRamBytes()).HR(),
+		NetIn:     ds.ByteSize(res.GetNetTrafficIn()).HR(),
+		NetOut:    ds.ByteSize(res.GetNetTrafficOut()).HR(),
+	}
+}
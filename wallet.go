@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	ethc "github.com/ethereum/go-ethereum/crypto"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+)
+
+// passphraseEnvVar holds the keystore passphrase, so it never has to be
+// typed on the command line or committed alongside the keystore file.
+const passphraseEnvVar = "SONM_BROWSER_PASSPHRASE"
+
+// defaultKeystorePath is where the browser's identity lives if --keystore
+// is not given.
+func defaultKeystorePath() string {
+	return "./keystore.json"
+}
+
+// loadOrCreateWallet loads the browser's persisted identity from path,
+// generating and saving a new one on first run. Without this, newApp used
+// to call ethc.GenerateKey() every startup, so the gRPC identity changed
+// on every restart and the browser could never place or cancel an order
+// it had previously created.
+func loadOrCreateWallet(path string) (*ecdsa.PrivateKey, error) {
+	passphrase := os.Getenv(passphraseEnvVar)
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		key, err := ethc.GenerateKey()
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot generate wallet key")
+		}
+
+		if err := saveKeystore(path, key, passphrase); err != nil {
+			return nil, errors.Wrap(err, "cannot save new keystore")
+		}
+
+		log.Printf("generated new wallet, address: %s\r\n", ethc.PubkeyToAddress(key.PublicKey).Hex())
+		return key, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "cannot stat keystore file")
+	}
+
+	return loadKeystore(path, passphrase)
+}
+
+func loadKeystore(path, passphrase string) (*ecdsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read keystore file")
+	}
+
+	key, err := keystore.DecryptKey(data, passphrase)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decrypt keystore")
+	}
+
+	return key.PrivateKey, nil
+}
+
+func saveKeystore(path string, key *ecdsa.PrivateKey, passphrase string) error {
+	data, err := keystore.EncryptKey(&keystore.Key{
+		Id:         uuid.NewRandom(),
+		Address:    ethc.PubkeyToAddress(key.PublicKey),
+		PrivateKey: key,
+	}, passphrase, keystore.StandardScryptN, keystore.StandardScryptP)
+	if err != nil {
+		return errors.Wrap(err, "cannot encrypt keystore")
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// runWalletCommand implements the `wallet new|import|address` CLI
+// subcommands used to manage the keystore outside of the web server.
+func runWalletCommand(args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: wallet <new|import|address> [--keystore path]")
+	}
+
+	fs := flag.NewFlagSet("wallet", flag.ExitOnError)
+	path := fs.String("keystore", defaultKeystorePath(), "path to the encrypted keystore file")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "new":
+		return walletNew(*path)
+	case "import":
+		return walletImport(*path)
+	case "address":
+		return walletAddress(*path)
+	default:
+		return errors.Errorf("unknown wallet subcommand %q", args[0])
+	}
+}
+
+func walletNew(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return errors.Errorf("keystore %q already exists", path)
+	}
+
+	key, err := ethc.GenerateKey()
+	if err != nil {
+		return errors.Wrap(err, "cannot generate wallet key")
+	}
+
+	if err := saveKeystore(path, key, os.Getenv(passphraseEnvVar)); err != nil {
+		return err
+	}
+
+	fmt.Printf("wallet created: %s\n", ethc.PubkeyToAddress(key.PublicKey).Hex())
+	return nil
+}
+
+func walletImport(path string) error {
+	fmt.Print("private key (hex): ")
+	var hexKey string
+	if _, err := fmt.Scanln(&hexKey); err != nil {
+		return errors.Wrap(err, "cannot read private key")
+	}
+
+	key, err := ethc.HexToECDSA(strings.TrimPrefix(hexKey, "0x"))
+	if err != nil {
+		return errors.Wrap(err, "cannot parse private key")
+	}
+
+	if err := saveKeystore(path, key, os.Getenv(passphraseEnvVar)); err != nil {
+		return err
+	}
+
+	fmt.Printf("wallet imported: %s\n", ethc.PubkeyToAddress(key.PublicKey).Hex())
+	return nil
+}
+
+func walletAddress(path string) error {
+	key, err := loadKeystore(path, os.Getenv(passphraseEnvVar))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(ethc.PubkeyToAddress(key.PublicKey).Hex())
+	return nil
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo"
+	pb "github.com/sonm-io/core/proto"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingPeriod     = (wsPongWait * 9) / 10
+	wsSendBufferSize = 16
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// wsMessage is a single frame pushed to a /ws/orders subscriber.
+type wsMessage struct {
+	Event string `json:"event"` // "add" or "remove"
+	Order *row   `json:"order,omitempty"`
+	ID    string `json:"id,omitempty"`
+}
+
+// orderFilter reports whether an order matches a subscriber's query params.
+// It is compiled once per connection from buildOrderFilter so matching one
+// order against one subscriber is a handful of cheap comparisons; a poll
+// tick runs every subscriber's filter for every changed order.
+type orderFilter func(o *pb.Order) bool
+
+// buildOrderFilter compiles the type, cpu, ram, gpu and net_type query
+// params into a predicate, used to decide which /ws/orders subscribers a
+// newly seen order is pushed to. The other fields reqContextToOrder accepts
+// (client_id, pps, duration, storage, net_in, net_out) are not matched on.
+// Unlike reqContextToOrder, every field is optional: omitted or unparseable
+// fields place no constraint on the match.
+func buildOrderFilter(c echo.Context) orderFilter {
+	var preds []orderFilter
+
+	if v := c.QueryParam("type"); v != "" {
+		if t, err := strconv.ParseInt(v, 10, 64); err == nil {
+			want := pb.OrderType(t)
+			preds = append(preds, func(o *pb.Order) bool { return o.GetOrderType() == want })
+		}
+	}
+
+	if v := c.QueryParam("cpu"); v != "" {
+		if min, err := strconv.ParseUint(v, 10, 64); err == nil {
+			preds = append(preds, func(o *pb.Order) bool { return o.GetSlot().GetResources().GetCpuCores() >= min })
+		}
+	}
+
+	if v := c.QueryParam("ram"); v != "" {
+		if min, err := strconv.ParseUint(v, 10, 64); err == nil {
+			preds = append(preds, func(o *pb.Order) bool { return o.GetSlot().GetResources().GetRamBytes() >= min })
+		}
+	}
+
+	if v := c.QueryParam("gpu"); v != "" {
+		if min, err := strconv.ParseInt(v, 10, 64); err == nil {
+			preds = append(preds, func(o *pb.Order) bool { return int64(o.GetSlot().GetResources().GetGpuCount()) >= min })
+		}
+	}
+
+	if v := c.QueryParam("net_type"); v != "" {
+		if nt, err := strconv.ParseInt(v, 10, 64); err == nil {
+			want := pb.NetworkType(nt)
+			preds = append(preds, func(o *pb.Order) bool { return o.GetSlot().GetResources().GetNetworkType() == want })
+		}
+	}
+
+	return func(o *pb.Order) bool {
+		for _, p := range preds {
+			if !p(o) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// orderHub fans out marketplace order changes to every connected
+// /ws/orders subscriber whose filter matches.
+type orderHub struct {
+	mu   sync.Mutex
+	subs map[*orderSubscriber]struct{}
+}
+
+func newOrderHub() *orderHub {
+	return &orderHub{subs: make(map[*orderSubscriber]struct{})}
+}
+
+// orderSubscriber is one open /ws/orders connection. send is bounded so a
+// slow consumer can't make the poller block; once full, the subscriber is
+// dropped instead.
+type orderSubscriber struct {
+	filter orderFilter
+	send   chan []byte
+}
+
+func (h *orderHub) subscribe(filter orderFilter) *orderSubscriber {
+	s := &orderSubscriber{filter: filter, send: make(chan []byte, wsSendBufferSize)}
+
+	h.mu.Lock()
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+
+	return s
+}
+
+func (h *orderHub) unsubscribe(s *orderSubscriber) {
+	h.mu.Lock()
+	delete(h.subs, s)
+	h.mu.Unlock()
+}
+
+func (h *orderHub) publish(event string, o *pb.Order) {
+	msg := wsMessage{Event: event}
+	if event == "remove" {
+		msg.ID = o.GetId()
+	} else {
+		msg.Order = orderToRow(o)
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("cannot marshal ws message: %v\r\n", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subs {
+		if !s.filter(o) {
+			continue
+		}
+
+		select {
+		case s.send <- data:
+		default:
+			log.Println("ws subscriber too slow, dropping connection")
+			delete(h.subs, s)
+			close(s.send)
+		}
+	}
+}
+
+// pollOrders polls the marketplace every interval, diffs the snapshot
+// against the previous one keyed by order ID, and publishes add/remove
+// events for the difference. It runs for the lifetime of the App.
+func pollOrders(app *App, hub *orderHub, interval time.Duration) {
+	prev := make(map[string]*pb.Order)
+
+	for {
+		req := &pb.GetOrdersRequest{
+			Order: &pb.Order{
+				PricePerSecond: pb.NewBigIntFromInt(1),
+				Slot:           &pb.Slot{Resources: &pb.Resources{}},
+			},
+			Count: 1000,
+		}
+
+		resp, err := app.market.GetOrders(app.ctx, req)
+		if err != nil {
+			log.Printf("ws poller: cannot retrieve orders: %v\r\n", err)
+			time.Sleep(interval)
+			continue
+		}
+
+		next := make(map[string]*pb.Order, len(resp.GetOrders()))
+		for _, o := range resp.GetOrders() {
+			next[o.GetId()] = o
+		}
+
+		for id, o := range next {
+			if _, ok := prev[id]; !ok {
+				hub.publish("add", o)
+			}
+		}
+		for id, o := range prev {
+			if _, ok := next[id]; !ok {
+				hub.publish("remove", o)
+			}
+		}
+
+		prev = next
+		time.Sleep(interval)
+	}
+}
+
+// registerOrderStreamRoutes mounts /ws/orders and starts the background
+// poller that feeds it.
+func registerOrderStreamRoutes(e *echo.Echo, app *App, pollInterval time.Duration) {
+	hub := newOrderHub()
+	go pollOrders(app, hub, pollInterval)
+
+	e.GET("/ws/orders", func(c echo.Context) error {
+		conn, err := wsUpgrader.Upgrade(c.Response(), c.Request(), nil)
+		if err != nil {
+			log.Printf("cannot upgrade ws connection: %v\r\n", err)
+			return err
+		}
+
+		sub := hub.subscribe(buildOrderFilter(c))
+		go serveOrderStream(conn, hub, sub)
+
+		return nil
+	})
+}
+
+// serveOrderStream writes hub messages to conn, handling ping/pong and
+// per-connection write deadlines, until the connection drops or the
+// subscriber is disconnected as a slow consumer.
+func serveOrderStream(conn *websocket.Conn, hub *orderHub, sub *orderSubscriber) {
+	defer func() {
+		hub.unsubscribe(sub)
+		conn.Close()
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		return nil
+	})
+
+	// this is a push-only stream, but we still need to keep reading to
+	// process pong frames and notice the client going away.
+	go func() {
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case data, ok := <-sub.send:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}